@@ -2,50 +2,457 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
+	"io/fs"
 	"log"
+	"math"
 	"os"
+	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+
+	"submod/subtitles"
 )
 
-// Creates a new subtitle file from the inputfile, but with all the time fields
-// incremented by 'seconds' seconds (decremented when negative).
+// Creates a new subtitle file (or, in batch mode, a whole tree of them)
+// with all the time fields transformed according to a constant offset, an
+// fps conversion, or a two-point resync.
+//
+// Offset mode (default): "submod inputfile seconds" shifts every time field
+// by 'seconds' seconds (back in time when negative). The name of the new
+// file is identical to the old one, but prepended with "{+x.xx_Sec}_".
+//
+// Resync mode: "submod -first HH:MM:SS,mmm -last HH:MM:SS,mmm inputfile"
+// takes the desired display time of the first and last subtitle, and
+// derives a linear transform new_t = a*t + b from the file's actual first
+// and last cue times. This corrects framerate-drift mismatches (e.g.
+// 23.976 vs 25 fps) that a pure offset can't fix. The new file is
+// prepended with "{resync}_".
+//
+// Fps mode: "submod -from-fps 23.976 -to-fps 25 inputfile" scales every
+// time field by from/to, for subtitles authored against a differently
+// telecined release. The new file is prepended with "{fps}_".
 //
-// The name of the new file is identical to the old one, but prepended with "{+x.xx_Sec}_".
+// Any of the above can be combined with "-fix-overlap", which afterwards
+// walks the cues in order and truncates each cue's end time to the next
+// cue's start time where they overlap. This fixes the classic artifact of
+// auto-generated VTT captions converted to SRT via ffmpeg, where every
+// cue's end bleeds into the following cue. The new file additionally gets
+// a "{fixed}_" prefix. (Not supported for .ass/.ssa.)
+//
+// .ass/.ssa files go through the same offset/fps/resync transform, but
+// are rewritten line by line instead of through the subtitles package:
+// only the Start/End fields of each Dialogue: line are touched, leaving
+// styles, fonts and formatting overrides untouched.
+//
+// Batch mode: "submod -r ./season1 +2.5" applies the same transform to
+// every .srt/.vtt/.ass/.ssa file under a directory; "-include" filters
+// which filenames qualify, and "-in-place" overwrites each source file
+// instead of writing a prefixed copy. A per-file summary is printed as
+// each file completes, followed by a final aggregate.
 func main() {
-	if len(os.Args) < 3 {
+	firstFlag := flag.String("first", "", "desired display time of the first subtitle (HH:MM:SS,mmm); requires -last")
+	lastFlag := flag.String("last", "", "desired display time of the last subtitle (HH:MM:SS,mmm); requires -first")
+	fromFpsFlag := flag.Float64("from-fps", 0, "framerate the subtitle was authored for; requires -to-fps")
+	toFpsFlag := flag.Float64("to-fps", 0, "framerate to convert the subtitle to; requires -from-fps")
+	fixOverlapFlag := flag.Bool("fix-overlap", false, "truncate each cue's end time to the next cue's start time where they overlap")
+	recursiveFlag := flag.Bool("r", false, "when inputfile is a directory, recurse into its subdirectories (batch mode)")
+	includeFlag := flag.String("include", "", "glob pattern filenames must match in batch mode, e.g. '*.en.srt'")
+	inPlaceFlag := flag.Bool("in-place", false, "overwrite the source file(s) instead of writing prefixed copies")
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) < 1 {
 		log.Fatal("\nUsage: submod inputfile seconds\n",
+			"   or: submod -first HH:MM:SS,mmm -last HH:MM:SS,mmm inputfile\n",
+			"   or: submod -from-fps fps -to-fps fps inputfile\n",
+			"   or: submod -r directory seconds\n",
 			"The following arguments are required: inputfile, seconds")
 	}
 
-	var inputfile string = os.Args[1]
-	var outputfile string
-	var deleted_subs int
-	var seconds float64
+	var inputfile string = args[0]
 
-	seconds, err := strconv.ParseFloat(os.Args[2], 64)
+	info, err := os.Stat(inputfile)
 	if err != nil {
-		log.Fatal("\nUsage: submod inputfile seconds\n" +
-			"The seconds field should be numeric.")
+		log.Fatal(err)
 	}
 
-	if strings.HasSuffix(inputfile, ".srt") {
-		outputfile = name_output(inputfile, seconds)
-		deleted_subs = convert_srt(inputfile, outputfile, seconds)
-	} else if strings.HasSuffix(inputfile, ".vtt") {
-		outputfile = name_output(inputfile, seconds)
-		deleted_subs = convert_vtt(inputfile, outputfile, seconds)
-	} else {
-		fmt.Println("Please specify either an .srt or .vtt file as input.")
-		os.Exit(1)
+	var opts transform_opts = transform_opts{
+		first_flag:  *firstFlag,
+		last_flag:   *lastFlag,
+		from_fps:    *fromFpsFlag,
+		to_fps:      *toFpsFlag,
+		fix_overlap: *fixOverlapFlag,
+		in_place:    *inPlaceFlag,
+	}
+
+	if info.IsDir() {
+		if len(args) >= 2 {
+			opts.seconds, err = strconv.ParseFloat(args[1], 64)
+			if err != nil {
+				log.Fatal("\nThe seconds field should be numeric.")
+			}
+		}
+		if len(args) < 2 && opts.first_flag == "" && opts.from_fps == 0 && !opts.fix_overlap {
+			log.Fatal("\nUsage: submod -r directory seconds\n",
+				"The following arguments are required: directory, seconds")
+		}
+
+		run_batch(inputfile, *recursiveFlag, *includeFlag, opts)
+		return
 	}
 
+	if len(args) < 2 && opts.first_flag == "" && opts.from_fps == 0 && !opts.fix_overlap {
+		log.Fatal("\nUsage: submod inputfile seconds\n",
+			"The following arguments are required: inputfile, seconds")
+	}
+	if len(args) >= 2 {
+		opts.seconds, err = strconv.ParseFloat(args[1], 64)
+		if err != nil {
+			log.Fatal("\nUsage: submod inputfile seconds\n" +
+				"The seconds field should be numeric.")
+		}
+	}
+
+	outputfile, deleted_subs, shortened, err := process_file(inputfile, opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if shortened > 0 {
+		fmt.Printf("Shortened %d overlapping subtitle(s).\n", shortened)
+	}
 	status(deleted_subs, outputfile)
 }
 
+// transform_opts bundles the CLI flags that determine how a single file is
+// transformed, so that process_file can be shared between single-file and
+// batch mode.
+type transform_opts struct {
+	first_flag  string
+	last_flag   string
+	from_fps    float64
+	to_fps      float64
+	seconds     float64
+	fix_overlap bool
+	in_place    bool
+}
+
+// Walks root (recursing into subdirectories when recursive is true),
+// applies opts to every .srt/.vtt/.ass/.ssa file whose name matches
+// include (or every such file, when include is empty), and prints a
+// per-file summary followed by a final aggregate.
+func run_batch(root string, recursive bool, include string, opts transform_opts) {
+	files, err := collect_batch_files(root, recursive, include)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(files) == 0 {
+		fmt.Println("No matching subtitle files found under", root)
+		return
+	}
+
+	var total_deleted, total_shortened, failed int
+
+	for _, inputfile := range files {
+		outputfile, deleted_subs, shortened, err := process_file(inputfile, opts)
+		if err != nil {
+			fmt.Println("FAILED:", inputfile, "-", err)
+			failed += 1
+			continue
+		}
+
+		fmt.Printf("%s -> %s (%d subtitle(s) deleted", inputfile, outputfile, deleted_subs)
+		if shortened > 0 {
+			fmt.Printf(", %d overlap(s) fixed", shortened)
+		}
+		fmt.Println(")")
+
+		total_deleted += deleted_subs
+		total_shortened += shortened
+	}
+
+	fmt.Printf("\nProcessed %d/%d file(s): %d subtitle(s) deleted", len(files)-failed, len(files), total_deleted)
+	if total_shortened > 0 {
+		fmt.Printf(", %d overlap(s) fixed", total_shortened)
+	}
+	fmt.Println(".")
+}
+
+// Collects every .srt/.vtt/.ass/.ssa file under root whose base name
+// matches the include glob pattern (or every such file, when include is
+// empty), sorted for deterministic output. Subdirectories are only
+// visited when recursive is true.
+func collect_batch_files(root string, recursive bool, include string) ([]string, error) {
+	var files []string
+
+	add := func(path string) error {
+		if !is_subtitle_file(path) {
+			return nil
+		}
+		matched, err := matches_include(path, include)
+		if err != nil {
+			return err
+		}
+		if matched {
+			files = append(files, path)
+		}
+		return nil
+	}
+
+	if recursive {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			return add(path)
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		entries, err := os.ReadDir(root)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			if err := add(filepath.Join(root, entry.Name())); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+func is_subtitle_file(path string) bool {
+	for _, ext := range []string{".srt", ".vtt", ".ass", ".ssa"} {
+		if strings.HasSuffix(path, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+func matches_include(path string, include string) (bool, error) {
+	if include == "" {
+		return true, nil
+	}
+	return filepath.Match(include, filepath.Base(path))
+}
+
+// Transforms a single subtitle file according to opts, writing the result
+// either to a prefixed copy or (when opts.in_place is set) back over
+// inputfile, and returns the path written to along with the number of
+// subtitles dropped and, if -fix-overlap was requested, the number of
+// cues shortened.
+func process_file(inputfile string, opts transform_opts) (string, int, int, error) {
+	var is_vtt, is_ass bool
+
+	switch {
+	case strings.HasSuffix(inputfile, ".srt"):
+		is_vtt = false
+	case strings.HasSuffix(inputfile, ".vtt"):
+		is_vtt = true
+	case strings.HasSuffix(inputfile, ".ass"), strings.HasSuffix(inputfile, ".ssa"):
+		is_ass = true
+	default:
+		return "", 0, 0, fmt.Errorf("not a recognized subtitle file (.srt, .vtt, .ass, .ssa): %s", inputfile)
+	}
+
+	if opts.fix_overlap && is_ass {
+		return "", 0, 0, fmt.Errorf("-fix-overlap is not supported for .ass/.ssa files: %s", inputfile)
+	}
+
+	var file *subtitles.File
+	var err error
+	if !is_ass {
+		file, err = read_file(inputfile, is_vtt)
+		if err != nil {
+			return "", 0, 0, err
+		}
+	}
+
+	var a, b float64
+	var outputfile string
+
+	switch {
+	case opts.first_flag != "" || opts.last_flag != "":
+		if opts.first_flag == "" || opts.last_flag == "" {
+			return "", 0, 0, fmt.Errorf("-first and -last must be given together for resync mode")
+		}
+
+		want_first, err := subtitles.ParseTime(opts.first_flag)
+		if err != nil {
+			return "", 0, 0, fmt.Errorf("error processing -first: %w", err)
+		}
+		want_last, err := subtitles.ParseTime(opts.last_flag)
+		if err != nil {
+			return "", 0, 0, fmt.Errorf("error processing -last: %w", err)
+		}
+
+		var have_first, have_last time.Duration
+		if is_ass {
+			have_first, have_last, err = scan_ass_anchors(inputfile)
+			if err != nil {
+				return "", 0, 0, fmt.Errorf("error scanning subtitle anchors: %w", err)
+			}
+		} else {
+			if len(file.Subtitles) == 0 {
+				return "", 0, 0, fmt.Errorf("cannot resync: %s has no subtitles", inputfile)
+			}
+			have_first = file.Subtitles[0].Start
+			have_last = file.Subtitles[len(file.Subtitles)-1].Start
+		}
+		if have_last == have_first {
+			return "", 0, 0, fmt.Errorf("cannot resync: the first and last subtitle have the same timestamp")
+		}
+
+		a = (want_last - want_first).Seconds() / (have_last - have_first).Seconds()
+		b = want_first.Seconds() - a*have_first.Seconds()
+		outputfile = name_output_resync(inputfile)
+	case opts.from_fps != 0 || opts.to_fps != 0:
+		if opts.from_fps == 0 || opts.to_fps == 0 {
+			return "", 0, 0, fmt.Errorf("-from-fps and -to-fps must be given together for fps conversion mode")
+		}
+
+		a = opts.from_fps / opts.to_fps
+		b = 0
+		outputfile = name_output_fps(inputfile)
+	default:
+		a = 1
+		b = opts.seconds
+		outputfile = name_output(inputfile, opts.seconds)
+	}
+
+	if !is_ass && opts.fix_overlap {
+		var dir, base string = filepath.Split(outputfile)
+		outputfile = dir + "{fixed}_" + base
+	}
+	if opts.in_place {
+		outputfile = inputfile
+	}
+
+	var deleted_subs, shortened int
+	var tmpfile string = outputfile + ".submod-tmp"
+
+	if is_ass {
+		deleted_subs, err = convert_ass(inputfile, tmpfile, a, b)
+		if err != nil {
+			os.Remove(tmpfile)
+			return "", 0, 0, err
+		}
+	} else {
+		deleted_subs = apply_transform(file, a, b)
+		if opts.fix_overlap {
+			shortened = fix_overlaps(file)
+		}
+		if err := write_file(file, tmpfile, is_vtt); err != nil {
+			os.Remove(tmpfile)
+			return "", 0, 0, err
+		}
+	}
+
+	if err := os.Rename(tmpfile, outputfile); err != nil {
+		return "", 0, 0, err
+	}
+
+	return outputfile, deleted_subs, shortened, nil
+}
+
+// Walks the file's subtitles in order and truncates each cue's end time to
+// the next cue's start time wherever they overlap, returning how many
+// cues were shortened.
+func fix_overlaps(file *subtitles.File) int {
+	var shortened int = 0
+
+	for i := 0; i < len(file.Subtitles)-1; i++ {
+		if file.Subtitles[i].End > file.Subtitles[i+1].Start {
+			file.Subtitles[i].End = file.Subtitles[i+1].Start
+			shortened += 1
+		}
+	}
+
+	return shortened
+}
+
+// Parses inputfile into a subtitles.File, using the SRT or VTT reader
+// depending on is_vtt.
+func read_file(inputfile string, is_vtt bool) (*subtitles.File, error) {
+	input, err := os.Open(inputfile)
+	if err != nil {
+		return nil, err
+	}
+	defer input.Close()
+
+	if is_vtt {
+		return subtitles.ReadFromVTT(input)
+	}
+	return subtitles.ReadFromSRT(input)
+}
+
+// Serializes file to outputfile, using the SRT or VTT writer depending on
+// is_vtt.
+func write_file(file *subtitles.File, outputfile string, is_vtt bool) error {
+	output, err := os.Create(outputfile)
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+
+	if is_vtt {
+		return file.WriteToVTT(output)
+	}
+	return file.WriteToSRT(output)
+}
+
+// Applies the linear transform new_t = a*t + b to the start and end time
+// of every subtitle in file, re-numbering the survivors and returning how
+// many were dropped.
+//
+// A plain offset is the special case a=1, b=seconds. When a cue's new end
+// time would still be negative, the subtitle is before the start of the
+// movie and gets dropped; when only its new start time is negative, the
+// start is clamped to zero instead, just like the original per-line
+// handling.
+func apply_transform(file *subtitles.File, a float64, b float64) int {
+	var kept []subtitles.Subtitle
+	var deleted_subs int = 0
+
+	for _, sub := range file.Subtitles {
+		new_start := a*sub.Start.Seconds() + b
+		new_end := a*sub.End.Seconds() + b
+
+		if new_end < 0 {
+			deleted_subs += 1
+			continue
+		}
+		if new_start < 0 {
+			new_start = 0
+		}
+
+		sub.Start = time.Duration(math.Round(new_start*1000)) * time.Millisecond
+		sub.End = time.Duration(math.Round(new_end*1000)) * time.Millisecond
+		sub.Index = len(kept) + 1
+		kept = append(kept, sub)
+	}
+
+	file.Subtitles = kept
+
+	return deleted_subs
+}
+
 // Determines the name of the outputfile based on the inputfile and seconds;
 // the name of the new file is identical to the old one, but prepended with "{+x.xx_Sec}_".
 //
@@ -53,13 +460,15 @@ func main() {
 // the 'increment number' x, instead of prepending "{+x.xx_Sec}_" a second time.
 // This way we can conveniently process files multiple times, and still have sensible names.
 func name_output(inputfile string, seconds float64) string {
+	var dir, base string = filepath.Split(inputfile)
+
 	// Regex to check if the inputfile was previously processed by submod:
 	proc, err := regexp.Compile(`\{[+-]\d+\.\d+_Sec\}_`)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	var processed bool = proc.MatchString(inputfile)
+	var processed bool = proc.MatchString(base)
 	var placeholder string
 	var incr float64
 
@@ -72,7 +481,7 @@ func name_output(inputfile string, seconds float64) string {
 		}
 
 		// FindString extracts the leftmost occurrence of 're'
-		var number string = re.FindString(inputfile)
+		var number string = re.FindString(base)
 
 		incr, err = strconv.ParseFloat(number, 64)
 		if err != nil {
@@ -83,268 +492,241 @@ func name_output(inputfile string, seconds float64) string {
 		// FindStringIndex returns the start
 		// to end indices of the leftmost occurrence of proc as a slice,
 		// which we then use to replace proc with the format:
-		index := proc.FindStringIndex(inputfile)
-		placeholder = "{%.2f_Sec}_" + inputfile[index[1]:]
+		index := proc.FindStringIndex(base)
+		placeholder = "{%.2f_Sec}_" + base[index[1]:]
 	} else {
 		incr = seconds
-		placeholder = "{%.2f_Sec}_" + inputfile
+		placeholder = "{%.2f_Sec}_" + base
 	}
 
 	if incr >= 0 {
 		placeholder = "{+" + placeholder[1:]
 	}
 
-	var outputfile string = fmt.Sprintf(placeholder, incr)
+	var outputbase string = fmt.Sprintf(placeholder, incr)
 
-	return outputfile
+	return dir + outputbase
 }
 
-// Loops through the given inputfile, modifies the lines consisting of the time encoding,
-// writes everything back to outputfile, and returns the number of subtitles that were deleted.
-//
-// This function is identical to convert_srt,
-// except that it uses '.' for the seconds field's decimal space.
-//
-// The subtitle files consist of a repetition of the following 3 lines:
+// Determines the name of the outputfile for resync mode; the name of the
+// new file is identical to the old one, but prepended with "{resync}_".
 //
-// - Index-line: integer count indicating line number
-// - Time-line: encoding the duration for which the subtitle appears
-// - Sub-line: the actual subtitle to appear on-screen (1 or 2 lines)
-//
-// Example .vtt (Note: '.' for decimal spaces):
-//
-// 1
-// 00:00:00.243 --> 00:00:02.110
-// Previously on ...
-//
-// 2
-// 00:00:03.802 --> 00:00:05.314
-// Etc.
-func convert_vtt(inputfile string, outputfile string, seconds float64) int {
-	input, err := os.Open(inputfile)
+// Like name_output, a file that was already processed by submod has its
+// old prefix stripped first, so repeated processing still yields sensible
+// names.
+func name_output_resync(inputfile string) string {
+	var dir, base string = filepath.Split(strip_submod_prefix(inputfile))
+	return dir + "{resync}_" + base
+}
+
+// Determines the name of the outputfile for fps conversion mode; the name
+// of the new file is identical to the old one, but prepended with
+// "{fps}_". Like name_output, a file that was already processed by submod
+// has its old prefix stripped first.
+func name_output_fps(inputfile string) string {
+	var dir, base string = filepath.Split(strip_submod_prefix(inputfile))
+	return dir + "{fps}_" + base
+}
+
+// Strips any "{+x.xx_Sec}_", "{resync}_", "{fps}_" or "{fixed}_" prefix
+// previously added by submod, so repeated processing still yields
+// sensible names. Only the base filename is considered, so any
+// directory component in inputfile is left untouched.
+func strip_submod_prefix(inputfile string) string {
+	var dir, base string = filepath.Split(inputfile)
+
+	offset_proc, err := regexp.Compile(`\{[+-]\d+\.\d+_Sec\}_`)
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer input.Close()
+	if index := offset_proc.FindStringIndex(base); index != nil {
+		base = base[index[1]:]
+	}
 
-	output, err := os.Create(outputfile)
+	other_proc, err := regexp.Compile(`\{(resync|fps|fixed)\}_`)
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer output.Close()
+	if index := other_proc.FindStringIndex(base); index != nil {
+		base = base[index[1]:]
+	}
+
+	return dir + base
+}
 
-	// Compile regex to find time-line:
-	re, err := regexp.Compile(`\d\d:\d\d:\d\d\.\d\d\d`)
+// Reads inputfile line by line, and for every "Dialogue:" line in the
+// [Events] section applies the linear transform new_t = a*t + b to its
+// Start and End fields, leaving every other field (Style, Name, Margins,
+// Effect, Text and its formatting overrides) and every non-Dialogue line
+// untouched. Writes the result to outputfile and returns the number of
+// dialogue lines dropped, using the same drop/clamp rule as apply_transform.
+//
+// This assumes the standard ASS/SSA Dialogue field order
+// (.../Start,End/...), which both "Format: Layer, Start, End, ..." (ASS)
+// and "Format: Marked, Start, End, ..." (SSA) share.
+func convert_ass(inputfile string, outputfile string, a float64, b float64) (int, error) {
+	input, err := os.Open(inputfile)
 	if err != nil {
-		log.Fatal(err)
+		return 0, err
 	}
+	defer input.Close()
+
+	output, err := os.Create(outputfile)
+	if err != nil {
+		return 0, err
+	}
+	defer output.Close()
 
 	var deleted_subs int = 0
-	var skip bool = false
 
-	// Iterate line by line over inputfile:
 	scanner := bufio.NewScanner(input)
 	for scanner.Scan() {
+		var line string = scanner.Text()
+		var new_line string = line
+		var dropped bool = false
 
-		var old_line string = scanner.Text()
-		var new_line string
-		var time_line bool = re.MatchString(old_line)
-
-		// Time-line: This is the line we need to modify
-		if time_line {
-			new_line = process_line(old_line, seconds)
-			if new_line == "(DELETED)\n" {
+		if strings.HasPrefix(strings.TrimSpace(line), "Dialogue:") {
+			new_line, dropped = process_ass_line(line, a, b)
+			if dropped {
 				deleted_subs += 1
-				skip = true
-			}
-		} else {
-			// When skip = True, subtitles are shifted too far back
-			// into the past (before the start of the movie),
-			// so they are deleted:
-			if skip {
-				// Subtitles can be 1 or 2 lines; we should only update
-				// skip when we have arrived at an empty line:
-				if old_line == "" {
-					skip = false
-				}
-				continue
-			} else {
-				new_line = old_line
 			}
 		}
 
+		if dropped {
+			continue
+		}
+
 		_, err = output.WriteString(new_line + "\n")
 		if err != nil {
-			log.Fatal(err)
+			return 0, err
 		}
 	}
 
 	if err := scanner.Err(); err != nil {
-		log.Fatal(err)
+		return 0, err
 	}
 
-	return deleted_subs
+	return deleted_subs, nil
 }
 
-// Loops through the given inputfile, modifies the lines consisting of the time encoding,
-// writes everything back to outputfile, and returns the number of subtitles that were deleted.
-//
-// This function is identical to convert_vtt,
-// except that it uses ',' for the seconds field's decimal space.
-//
-// The subtitle files consist of a repetition of the following 3 lines:
-//
-// - Index-line: integer count indicating line number
-// - Time-line: encoding the duration for which the subtitle appears
-// - Sub-line: the actual subtitle to appear on-screen (1 or 2 lines)
-//
-// Example .srt (Note: ',' for decimal spaces):
-//
-// 1
-// 00:00:00,243 --> 00:00:02,110
-// Previously on ...
-//
-// 2
-// 00:00:03,802 --> 00:00:05,314
-// Etc.
-func convert_srt(inputfile string, outputfile string, seconds float64) int {
-	input, err := os.Open(inputfile)
-	if err != nil {
-		log.Fatal(err)
+// Transforms the Start/End fields of a single "Dialogue:" line, returning
+// the new line and whether it should be dropped entirely (both its new
+// start and end time would be negative).
+func process_ass_line(line string, a float64, b float64) (string, bool) {
+	var prefix string = line[0 : strings.Index(line, "Dialogue:")+len("Dialogue:")]
+	var rest string = strings.TrimSpace(line[len(prefix):])
+
+	var fields []string = strings.SplitN(rest, ",", 10)
+	if len(fields) != 10 {
+		return line, false
 	}
-	defer input.Close()
 
-	output, err := os.Create(outputfile)
+	start, err := parse_ass_time(fields[1])
 	if err != nil {
-		log.Fatal(err)
+		return line, false
 	}
-	defer output.Close()
+	end, err := parse_ass_time(fields[2])
+	if err != nil {
+		return line, false
+	}
+
+	var new_start float64 = a*start.Seconds() + b
+	var new_end float64 = a*end.Seconds() + b
+
+	if new_end < 0 {
+		return line, true
+	}
+	if new_start < 0 {
+		new_start = 0
+	}
+
+	fields[1] = format_ass_time(time.Duration(math.Round(new_start*1000)) * time.Millisecond)
+	fields[2] = format_ass_time(time.Duration(math.Round(new_end*1000)) * time.Millisecond)
+
+	return prefix + " " + strings.Join(fields, ","), false
+}
 
-	// Compile regex to find time-line:
-	re, err := regexp.Compile(`\d\d:\d\d:\d\d,\d\d\d`)
+// Scans inputfile for the Start fields of its first and last Dialogue:
+// line, without modifying anything; used to derive the linear transform
+// for resync mode.
+func scan_ass_anchors(inputfile string) (time.Duration, time.Duration, error) {
+	input, err := os.Open(inputfile)
 	if err != nil {
-		log.Fatal(err)
+		return 0, 0, err
 	}
+	defer input.Close()
 
-	var deleted_subs int = 0
-	var skip bool = false
+	var first, last time.Duration
+	var found bool
 
-	// Iterate line by line over inputfile:
 	scanner := bufio.NewScanner(input)
 	for scanner.Scan() {
+		var line string = scanner.Text()
+		if !strings.HasPrefix(strings.TrimSpace(line), "Dialogue:") {
+			continue
+		}
 
-		var old_line string = scanner.Text()
-		var new_line string
-		var time_line bool = re.MatchString(old_line)
-
-		// Time-line: This is the line we need to modify
-		if time_line {
-			// We need '.' instead of ',' for floats!
-			new_line = strings.Replace(old_line, ",", ".", 2)
-			new_line = process_line(new_line, seconds)
-			if new_line == "(DELETED)\n" {
-				deleted_subs += 1
-				skip = true
-			} else {
-				// Convert back to '.srt' style:
-				new_line = strings.Replace(new_line, ".", ",", 2)
-			}
-		} else {
-			// When skip = True, subtitles are shifted too far back
-			// into the past (before the start of the movie),
-			// so they are deleted:
-			if skip {
-				// Subtitles can be 1 or 2 lines; we should only update
-				// skip when we have arrived at an empty line:
-				if old_line == "" {
-					skip = false
-				}
-				continue
-			} else {
-				new_line = old_line
-			}
+		var rest string = strings.TrimSpace(line[strings.Index(line, "Dialogue:")+len("Dialogue:"):])
+		var fields []string = strings.SplitN(rest, ",", 10)
+		if len(fields) != 10 {
+			continue
 		}
 
-		_, err = output.WriteString(new_line + "\n")
+		t, err := parse_ass_time(fields[1])
 		if err != nil {
-			log.Fatal(err)
+			continue
+		}
+
+		if !found {
+			first = t
+			found = true
 		}
+		last = t
 	}
 
 	if err := scanner.Err(); err != nil {
-		log.Fatal(err)
+		return 0, 0, err
+	}
+	if !found {
+		return 0, 0, fmt.Errorf("no Dialogue: lines found in %s", inputfile)
 	}
 
-	return deleted_subs
+	return first, last, nil
 }
 
-// Process the given line by adding seconds to start and end time.
-// (subtracting if seconds is negative)
-//
-// Example line:  '00:00:01.913 --> 00:00:04.328'
-// Index:          01234567890123456789012345678
-// Index by tens: (0)        10        20     (28)
-func process_line(line string, seconds float64) string {
-	var start string = line[0:12]
-	start = process_time(start, seconds)
-
-	var end string = line[17:29]
-	end = process_time(end, seconds)
-
-	if start == "(DELETED)\n" {
-		if end == "(DELETED)\n" {
-			line = "(DELETED)\n"
-		} else {
-			line = "00:00:00.000 --> " + end
-		}
-	} else {
-		line = start + " --> " + end
+// Parses an ASS/SSA time-string of the form 'H:MM:SS.cc' (centiseconds)
+// into a time.Duration.
+func parse_ass_time(time_string string) (time.Duration, error) {
+	var parts []string = strings.SplitN(strings.TrimSpace(time_string), ":", 3)
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("malformed ASS time-string: %q", time_string)
 	}
 
-	return line
-}
-
-// Increment the given time_string by 'incr' seconds
-//
-// The time-string has the form '00:00:00.000',
-// and converts to the following format string:
-// "%02d:%02d:%06.3f"
-func process_time(time_string string, incr float64) string {
-	hrs, err := strconv.Atoi(time_string[0:2])
+	hrs, err := strconv.Atoi(parts[0])
 	if err != nil {
-		log.Fatal("\nError processing hours:\n", err)
+		return 0, err
 	}
-	mins, err := strconv.Atoi(time_string[3:5])
+	mins, err := strconv.Atoi(parts[1])
 	if err != nil {
-		log.Fatal("\nError processing minutes:\n", err)
+		return 0, err
 	}
-	secs, err := strconv.ParseFloat(time_string[6:12], 64)
+	secs, err := strconv.ParseFloat(parts[2], 64)
 	if err != nil {
-		log.Fatal("\nError processing seconds:\n", err)
-	}
-
-	var hr time.Duration = time.Duration(hrs) * time.Hour
-	var min time.Duration = time.Duration(mins) * time.Minute
-	var sec time.Duration = time.Duration(secs*1000) * time.Millisecond
-	var delta time.Duration = time.Duration(incr*1000) * time.Millisecond
-	var new_time time.Duration = hr + min + sec + delta
-
-	// incr can be negative, so the new time could be too:
-	if new_time >= 0 {
-		// NOT casting to int64 might be problematic on 32 bit systems though:
-		// when int is 32 bits wide, it can't hold the largest of time.Duration values (which are 64 bit)!
-		// But this shouldn't be a problem for the small values we expect.
-		hrs = int(new_time / time.Hour)
-		mins = int((new_time % time.Hour) / time.Minute)
-		secs = float64((new_time%time.Minute)/time.Millisecond) / 1000
-		time_string = fmt.Sprintf("%02d:%02d:%06.3f", hrs, mins, secs)
-	} else {
-		// new_time < 0: the subtitles are now scheduled before the start
-		// of the movie, so we can delete them:
-		time_string = "(DELETED)\n"
+		return 0, err
 	}
 
-	return time_string
+	var total_secs float64 = float64(hrs)*3600 + float64(mins)*60 + secs
+	return time.Duration(math.Round(total_secs*1000)) * time.Millisecond, nil
+}
+
+// Formats a time.Duration back into the 'H:MM:SS.cc' ASS/SSA time-string
+// form (centiseconds, i.e. 2 decimal digits).
+func format_ass_time(d time.Duration) string {
+	var hrs int = int(d / time.Hour)
+	var mins int = int((d % time.Hour) / time.Minute)
+	var secs float64 = float64(d%time.Minute) / float64(time.Second)
+
+	return fmt.Sprintf("%d:%02d:%05.2f", hrs, mins, secs)
 }
 
 // Prints a status update for the user.
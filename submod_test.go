@@ -0,0 +1,315 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"submod/subtitles"
+)
+
+func TestApplyTransform(t *testing.T) {
+	cases := []struct {
+		name               string
+		a, b               float64
+		start, end         time.Duration
+		wantStart, wantEnd time.Duration
+		wantDeleted        int
+		wantKept           bool
+	}{
+		{
+			name: "plain offset", a: 1, b: 2.5,
+			start: 1 * time.Second, end: 3 * time.Second,
+			wantStart: 3500 * time.Millisecond, wantEnd: 5500 * time.Millisecond,
+			wantKept: true,
+		},
+		{
+			name: "start clamps to zero, end stays positive", a: 1, b: -2,
+			start: 1 * time.Second, end: 3 * time.Second,
+			wantStart: 0, wantEnd: 1 * time.Second,
+			wantKept: true,
+		},
+		{
+			name: "end still negative is dropped", a: 1, b: -5,
+			start: 1 * time.Second, end: 3 * time.Second,
+			wantDeleted: 1,
+		},
+		{
+			name: "fps scale", a: 23.976 / 25, b: 0,
+			start: 25 * time.Second, end: 50 * time.Second,
+			wantStart: time.Duration(25*(23.976/25)*1000) * time.Millisecond,
+			wantEnd:   time.Duration(50*(23.976/25)*1000) * time.Millisecond,
+			wantKept:  true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			file := &subtitles.File{Subtitles: []subtitles.Subtitle{
+				{Index: 1, Start: c.start, End: c.end, Lines: []string{"x"}},
+			}}
+
+			deleted := apply_transform(file, c.a, c.b)
+			if deleted != c.wantDeleted {
+				t.Fatalf("deleted = %d, want %d", deleted, c.wantDeleted)
+			}
+			if !c.wantKept {
+				if len(file.Subtitles) != 0 {
+					t.Fatalf("expected subtitle to be dropped, got %v", file.Subtitles)
+				}
+				return
+			}
+
+			if len(file.Subtitles) != 1 {
+				t.Fatalf("expected 1 surviving subtitle, got %d", len(file.Subtitles))
+			}
+			got := file.Subtitles[0]
+			if got.Start != c.wantStart {
+				t.Errorf("start = %v, want %v", got.Start, c.wantStart)
+			}
+			if got.End != c.wantEnd {
+				t.Errorf("end = %v, want %v", got.End, c.wantEnd)
+			}
+			if got.Index != 1 {
+				t.Errorf("expected re-numbered index 1, got %d", got.Index)
+			}
+		})
+	}
+}
+
+func TestFixOverlaps(t *testing.T) {
+	file := &subtitles.File{Subtitles: []subtitles.Subtitle{
+		{Index: 1, Start: 0, End: 3 * time.Second},
+		{Index: 2, Start: 2 * time.Second, End: 5 * time.Second},
+		{Index: 3, Start: 6 * time.Second, End: 8 * time.Second},
+	}}
+
+	shortened := fix_overlaps(file)
+	if shortened != 1 {
+		t.Fatalf("shortened = %d, want 1", shortened)
+	}
+	if file.Subtitles[0].End != 2*time.Second {
+		t.Errorf("expected overlapping cue truncated to 2s, got %v", file.Subtitles[0].End)
+	}
+	if file.Subtitles[1].End != 5*time.Second {
+		t.Errorf("non-overlapping cue should be untouched, got %v", file.Subtitles[1].End)
+	}
+}
+
+func TestNameOutput(t *testing.T) {
+	cases := []struct {
+		inputfile string
+		seconds   float64
+		want      string
+	}{
+		{"movie.srt", 2.5, "{+2.50_Sec}_movie.srt"},
+		{"movie.srt", -2.5, "{-2.50_Sec}_movie.srt"},
+		{"{+2.50_Sec}_movie.srt", 1, "{+3.50_Sec}_movie.srt"},
+	}
+
+	for _, c := range cases {
+		if got := name_output(c.inputfile, c.seconds); got != c.want {
+			t.Errorf("name_output(%q, %v) = %q, want %q", c.inputfile, c.seconds, got, c.want)
+		}
+	}
+}
+
+func TestNameOutputResyncAndFpsStripExistingPrefix(t *testing.T) {
+	if got := name_output_resync("{+2.50_Sec}_movie.srt"); got != "{resync}_movie.srt" {
+		t.Errorf("name_output_resync = %q", got)
+	}
+	if got := name_output_fps("{resync}_movie.srt"); got != "{fps}_movie.srt" {
+		t.Errorf("name_output_fps = %q", got)
+	}
+}
+
+func TestStripSubmodPrefix(t *testing.T) {
+	cases := map[string]string{
+		"movie.srt":                     "movie.srt",
+		"{+2.50_Sec}_movie.srt":         "movie.srt",
+		"{resync}_movie.srt":            "movie.srt",
+		"{fps}_movie.srt":               "movie.srt",
+		"{fixed}_{+2.50_Sec}_movie.srt": "movie.srt",
+		"dir/{fps}_movie.srt":           "dir/movie.srt",
+	}
+
+	for in, want := range cases {
+		if got := strip_submod_prefix(in); got != want {
+			t.Errorf("strip_submod_prefix(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestMatchesInclude(t *testing.T) {
+	cases := []struct {
+		path, include string
+		want          bool
+	}{
+		{"dir/movie.en.srt", "", true},
+		{"dir/movie.en.srt", "*.en.srt", true},
+		{"dir/movie.en.srt", "*.fr.srt", false},
+		{"dir/movie.en.srt", "movie.*", true},
+	}
+
+	for _, c := range cases {
+		got, err := matches_include(c.path, c.include)
+		if err != nil {
+			t.Fatalf("matches_include(%q, %q) returned error: %v", c.path, c.include, err)
+		}
+		if got != c.want {
+			t.Errorf("matches_include(%q, %q) = %v, want %v", c.path, c.include, got, c.want)
+		}
+	}
+}
+
+func TestCollectBatchFiles(t *testing.T) {
+	root := t.TempDir()
+	mustWrite(t, filepath.Join(root, "a.srt"), "")
+	mustWrite(t, filepath.Join(root, "b.en.srt"), "")
+	mustWrite(t, filepath.Join(root, "notes.txt"), "")
+	mustMkdir(t, filepath.Join(root, "sub"))
+	mustWrite(t, filepath.Join(root, "sub", "c.vtt"), "")
+
+	t.Run("non-recursive ignores subdirectories", func(t *testing.T) {
+		files, err := collect_batch_files(root, false, "")
+		if err != nil {
+			t.Fatalf("collect_batch_files returned error: %v", err)
+		}
+		want := []string{filepath.Join(root, "a.srt"), filepath.Join(root, "b.en.srt")}
+		sort.Strings(want)
+		if !equalStrings(files, want) {
+			t.Errorf("got %v, want %v", files, want)
+		}
+	})
+
+	t.Run("recursive descends into subdirectories", func(t *testing.T) {
+		files, err := collect_batch_files(root, true, "")
+		if err != nil {
+			t.Fatalf("collect_batch_files returned error: %v", err)
+		}
+		want := []string{
+			filepath.Join(root, "a.srt"),
+			filepath.Join(root, "b.en.srt"),
+			filepath.Join(root, "sub", "c.vtt"),
+		}
+		sort.Strings(want)
+		if !equalStrings(files, want) {
+			t.Errorf("got %v, want %v", files, want)
+		}
+	})
+
+	t.Run("include filters by base name", func(t *testing.T) {
+		files, err := collect_batch_files(root, false, "*.en.srt")
+		if err != nil {
+			t.Fatalf("collect_batch_files returned error: %v", err)
+		}
+		want := []string{filepath.Join(root, "b.en.srt")}
+		if !equalStrings(files, want) {
+			t.Errorf("got %v, want %v", files, want)
+		}
+	})
+}
+
+func mustWrite(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+func mustMkdir(t *testing.T, path string) {
+	t.Helper()
+	if err := os.Mkdir(path, 0755); err != nil {
+		t.Fatalf("failed to create dir %s: %v", path, err)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestProcessAssLine(t *testing.T) {
+	cases := []struct {
+		name        string
+		line        string
+		a, b        float64
+		wantLine    string
+		wantDropped bool
+	}{
+		{
+			name: "plain offset",
+			line: "Dialogue: 0,0:00:01.00,0:00:03.00,Default,,0,0,0,,Hi",
+			a:    1, b: 2.5,
+			wantLine: "Dialogue: 0,0:00:03.50,0:00:05.50,Default,,0,0,0,,Hi",
+		},
+		{
+			name: "fps scale",
+			line: "Dialogue: 0,0:00:25.00,0:00:50.00,Default,,0,0,0,,Hi",
+			a:    23.976 / 25, b: 0,
+			wantLine: "Dialogue: 0,0:00:23.98,0:00:47.95,Default,,0,0,0,,Hi",
+		},
+		{
+			name: "start clamps to zero, end stays positive",
+			line: "Dialogue: 0,0:00:01.00,0:00:03.00,Default,,0,0,0,,Hi",
+			a:    1, b: -2,
+			wantLine: "Dialogue: 0,0:00:00.00,0:00:01.00,Default,,0,0,0,,Hi",
+		},
+		{
+			name: "end still negative is dropped",
+			line: "Dialogue: 0,0:00:01.00,0:00:03.00,Default,,0,0,0,,Hi",
+			a:    1, b: -5,
+			wantDropped: true,
+		},
+		{
+			name: "commas in Text are preserved",
+			line: "Dialogue: 0,0:00:01.00,0:00:03.00,Default,,0,0,0,,Hi, there, you",
+			a:    1, b: 0,
+			wantLine: "Dialogue: 0,0:00:01.00,0:00:03.00,Default,,0,0,0,,Hi, there, you",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, dropped := process_ass_line(c.line, c.a, c.b)
+			if dropped != c.wantDropped {
+				t.Fatalf("dropped = %v, want %v", dropped, c.wantDropped)
+			}
+			if c.wantDropped {
+				return
+			}
+			if got != c.wantLine {
+				t.Errorf("got %q, want %q", got, c.wantLine)
+			}
+		})
+	}
+}
+
+func TestParseAndFormatAssTimeRoundTrip(t *testing.T) {
+	cases := []string{"0:00:00.00", "0:00:01.50", "1:23:45.67", "0:00:59.99"}
+
+	for _, s := range cases {
+		d, err := parse_ass_time(s)
+		if err != nil {
+			t.Fatalf("parse_ass_time(%q) returned error: %v", s, err)
+		}
+		if got := format_ass_time(d); got != s {
+			t.Errorf("format_ass_time(parse_ass_time(%q)) = %q, want %q", s, got, s)
+		}
+	}
+}
+
+func TestParseAssTimeMalformed(t *testing.T) {
+	if _, err := parse_ass_time("not-a-time"); err == nil {
+		t.Error("expected an error for a malformed ASS time-string, got nil")
+	}
+}
@@ -0,0 +1,251 @@
+// Package subtitles provides a small, format-agnostic model of a subtitle
+// file, along with readers and writers for SRT and VTT.
+package subtitles
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// arrowSep separates the start and end timestamp on a time-line.
+var arrowSep = []byte("-->")
+
+// bom is the UTF-8 encoding of a leading byte-order mark.
+var bom = []byte{0xEF, 0xBB, 0xBF}
+
+// Subtitle is a single cue: the time span during which Lines are displayed.
+type Subtitle struct {
+	Index int
+	Start time.Duration
+	End   time.Duration
+	Lines []string
+}
+
+// File is a parsed subtitle file. Header holds any content that precedes
+// the first cue (e.g. VTT's "WEBVTT" line); it is empty for SRT.
+type File struct {
+	Header    string
+	Subtitles []Subtitle
+}
+
+// ReadFromSRT parses an .srt file, whose time fields use ',' as the
+// decimal separator (e.g. '00:00:01,913').
+func ReadFromSRT(r io.Reader) (*File, error) {
+	return readFrom(r, ',')
+}
+
+// ReadFromVTT parses a .vtt file, whose time fields use '.' as the
+// decimal separator (e.g. '00:00:01.913').
+func ReadFromVTT(r io.Reader) (*File, error) {
+	return readFrom(r, '.')
+}
+
+// WriteToSRT serializes the file in .srt form.
+func (f *File) WriteToSRT(w io.Writer) error {
+	return f.writeTo(w, ',')
+}
+
+// WriteToVTT serializes the file in .vtt form.
+func (f *File) WriteToVTT(w io.Writer) error {
+	return f.writeTo(w, '.')
+}
+
+// readFrom parses cues out of r, one per blank-line-separated block.
+//
+// It tolerates the quirks real-world files tend to have: a missing
+// sequence-number line before the time-line (a bare time-line starts a
+// cue just as well), CRLF line endings, and a leading BOM. Sequence
+// numbers are re-derived on write, so a stray or missing index line is
+// simply ignored rather than rejected.
+//
+// Lines are read straight off a bufio.Reader and split with bytes.Cut
+// rather than bufio.Scanner, and a time-line is recognized by a
+// fixed-position byte check instead of a substring search: on large
+// batch jobs this keeps allocations and CPU down.
+func readFrom(r io.Reader, sep byte) (*File, error) {
+	file := &File{}
+
+	var header strings.Builder
+	var headerDone bool
+	var firstLine = true
+
+	var cue Subtitle
+	var inCue bool
+	var nextIndex = 1
+
+	flush := func() {
+		if inCue {
+			file.Subtitles = append(file.Subtitles, cue)
+			cue = Subtitle{}
+			inCue = false
+		}
+	}
+
+	br := bufio.NewReader(r)
+	for {
+		raw, readErr := br.ReadBytes('\n')
+
+		if len(raw) > 0 {
+			line := bytes.TrimRight(raw, "\r\n")
+			if firstLine {
+				line = bytes.TrimPrefix(line, bom)
+				firstLine = false
+			}
+
+			switch {
+			case isTimeLine(line):
+				start, end, err := parseTimeLine(line, sep)
+				if err != nil {
+					return nil, err
+				}
+				flush()
+				headerDone = true
+				cue = Subtitle{Index: nextIndex, Start: start, End: end}
+				nextIndex++
+				inCue = true
+
+			case len(line) == 0:
+				flush()
+
+			case inCue:
+				cue.Lines = append(cue.Lines, string(line))
+
+			case !headerDone && !isSequenceNumber(line):
+				header.Write(line)
+				header.WriteByte('\n')
+
+			case isSequenceNumber(line):
+				// A lone sequence-number line between cues; discarded,
+				// since WriteTo* re-derives indices on output.
+
+			default:
+				return nil, fmt.Errorf("subtitles: unexpected line before first cue: %q", line)
+			}
+		}
+
+		if readErr != nil {
+			if readErr == io.EOF {
+				break
+			}
+			return nil, readErr
+		}
+	}
+	flush()
+
+	file.Header = header.String()
+	return file, nil
+}
+
+// isTimeLine reports whether line starts with a 'HH:MM:SS' timestamp,
+// checked by fixed byte position rather than a compiled regex.
+func isTimeLine(line []byte) bool {
+	return len(line) > 8 && line[2] == ':' && line[5] == ':' && (line[8] == '.' || line[8] == ',')
+}
+
+func isSequenceNumber(line []byte) bool {
+	_, err := strconv.Atoi(string(line))
+	return err == nil
+}
+
+// parseTimeLine splits a "start --> end[ cue-settings]" line and parses
+// both timestamps. Trailing VTT cue settings after the end timestamp are
+// ignored.
+func parseTimeLine(line []byte, sep byte) (time.Duration, time.Duration, error) {
+	before, after, found := bytes.Cut(line, arrowSep)
+	if !found {
+		return 0, 0, fmt.Errorf("subtitles: malformed time-line: %q", line)
+	}
+
+	start, err := ParseTime(string(bytes.TrimSpace(before)))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	endField := bytes.TrimSpace(after)
+	if i := bytes.IndexByte(endField, ' '); i != -1 {
+		endField = endField[:i]
+	}
+	end, err := ParseTime(string(endField))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return start, end, nil
+}
+
+// ParseTime parses a single timestamp of the form '00:00:00.000' or
+// '00:00:00,000' into a time.Duration.
+func ParseTime(s string) (time.Duration, error) {
+	if len(s) < 12 {
+		return 0, fmt.Errorf("subtitles: malformed time-string: %q", s)
+	}
+
+	s = strings.Replace(s, ",", ".", 1)
+
+	hrs, err := strconv.Atoi(s[0:2])
+	if err != nil {
+		return 0, err
+	}
+	mins, err := strconv.Atoi(s[3:5])
+	if err != nil {
+		return 0, err
+	}
+	secs, err := strconv.ParseFloat(s[6:12], 64)
+	if err != nil {
+		return 0, err
+	}
+
+	var total_secs float64 = float64(hrs)*3600 + float64(mins)*60 + secs
+	return time.Duration(math.Round(total_secs*1000)) * time.Millisecond, nil
+}
+
+// formatTime formats a time.Duration back into a '00:00:00.000'
+// time-string, using sep for the decimal separator.
+func formatTime(d time.Duration, sep byte) string {
+	var hrs int = int(d / time.Hour)
+	var mins int = int((d % time.Hour) / time.Minute)
+	var secs float64 = float64(d%time.Minute) / float64(time.Second)
+
+	s := fmt.Sprintf("%02d:%02d:%06.3f", hrs, mins, secs)
+	if sep == ',' {
+		s = strings.Replace(s, ".", ",", 1)
+	}
+	return s
+}
+
+func (f *File) writeTo(w io.Writer, sep byte) error {
+	bw := bufio.NewWriter(w)
+
+	if f.Header != "" {
+		if _, err := bw.WriteString(f.Header + "\n"); err != nil {
+			return err
+		}
+	}
+
+	for i, sub := range f.Subtitles {
+		if _, err := fmt.Fprintf(bw, "%d\n", sub.Index); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(bw, "%s --> %s\n", formatTime(sub.Start, sep), formatTime(sub.End, sep)); err != nil {
+			return err
+		}
+		for _, line := range sub.Lines {
+			if _, err := fmt.Fprintf(bw, "%s\n", line); err != nil {
+				return err
+			}
+		}
+		if i != len(f.Subtitles)-1 {
+			if _, err := bw.WriteString("\n"); err != nil {
+				return err
+			}
+		}
+	}
+
+	return bw.Flush()
+}
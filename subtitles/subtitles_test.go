@@ -0,0 +1,113 @@
+package subtitles
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadFromSRT(t *testing.T) {
+	input := "1\n00:00:00,243 --> 00:00:02,110\nPreviously on ...\n\n" +
+		"2\n00:00:03,802 --> 00:00:05,314\nEtc.\n"
+
+	file, err := ReadFromSRT(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ReadFromSRT returned error: %v", err)
+	}
+	if len(file.Subtitles) != 2 {
+		t.Fatalf("expected 2 subtitles, got %d", len(file.Subtitles))
+	}
+
+	first := file.Subtitles[0]
+	if first.Start != 243*time.Millisecond {
+		t.Errorf("expected start 243ms, got %v", first.Start)
+	}
+	if first.End != 2*time.Second+110*time.Millisecond {
+		t.Errorf("expected end 2.110s, got %v", first.End)
+	}
+	if len(first.Lines) != 1 || first.Lines[0] != "Previously on ..." {
+		t.Errorf("unexpected lines: %v", first.Lines)
+	}
+}
+
+func TestReadFromSRTMissingIndex(t *testing.T) {
+	// A bare time-line with no preceding sequence number should still
+	// start a cue.
+	input := "00:00:00,000 --> 00:00:01,000\nHello\n"
+
+	file, err := ReadFromSRT(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ReadFromSRT returned error: %v", err)
+	}
+	if len(file.Subtitles) != 1 {
+		t.Fatalf("expected 1 subtitle, got %d", len(file.Subtitles))
+	}
+	if file.Subtitles[0].Index != 1 {
+		t.Errorf("expected auto-assigned index 1, got %d", file.Subtitles[0].Index)
+	}
+}
+
+func TestReadFromSRTCRLFAndBOM(t *testing.T) {
+	input := "\uFEFF1\r\n00:00:00,000 --> 00:00:01,000\r\nHello\r\n"
+
+	file, err := ReadFromSRT(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ReadFromSRT returned error: %v", err)
+	}
+	if len(file.Subtitles) != 1 {
+		t.Fatalf("expected 1 subtitle, got %d", len(file.Subtitles))
+	}
+	if file.Subtitles[0].Lines[0] != "Hello" {
+		t.Errorf("expected line %q, got %q", "Hello", file.Subtitles[0].Lines[0])
+	}
+}
+
+func TestWriteToSRTRoundTrip(t *testing.T) {
+	file := &File{Subtitles: []Subtitle{
+		{Index: 1, Start: 243 * time.Millisecond, End: 2*time.Second + 110*time.Millisecond, Lines: []string{"Previously on ..."}},
+		{Index: 2, Start: 3*time.Second + 802*time.Millisecond, End: 5*time.Second + 314*time.Millisecond, Lines: []string{"Etc."}},
+	}}
+
+	var buf strings.Builder
+	if err := file.WriteToSRT(&buf); err != nil {
+		t.Fatalf("WriteToSRT returned error: %v", err)
+	}
+
+	want := "1\n00:00:00,243 --> 00:00:02,110\nPreviously on ...\n\n" +
+		"2\n00:00:03,802 --> 00:00:05,314\nEtc.\n"
+	if buf.String() != want {
+		t.Errorf("WriteToSRT output mismatch:\ngot:  %q\nwant: %q", buf.String(), want)
+	}
+}
+
+func TestReadFromVTTHeader(t *testing.T) {
+	input := "WEBVTT\n\n1\n00:00:00.000 --> 00:00:01.000\nHello\n"
+
+	file, err := ReadFromVTT(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ReadFromVTT returned error: %v", err)
+	}
+	if file.Header != "WEBVTT\n" {
+		t.Errorf("expected header %q, got %q", "WEBVTT\n", file.Header)
+	}
+	if len(file.Subtitles) != 1 {
+		t.Fatalf("expected 1 subtitle, got %d", len(file.Subtitles))
+	}
+}
+
+func TestReadFromSRTArrowInText(t *testing.T) {
+	// A line of dialogue containing "-->" should not be mistaken for a
+	// time-line; only a line that actually starts with a timestamp is.
+	input := "1\n00:00:00,000 --> 00:00:01,000\nHe said --> go\n"
+
+	file, err := ReadFromSRT(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ReadFromSRT returned error: %v", err)
+	}
+	if len(file.Subtitles) != 1 {
+		t.Fatalf("expected 1 subtitle, got %d", len(file.Subtitles))
+	}
+	if file.Subtitles[0].Lines[0] != "He said --> go" {
+		t.Errorf("expected line %q, got %q", "He said --> go", file.Subtitles[0].Lines[0])
+	}
+}